@@ -0,0 +1,89 @@
+// main.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	dsstore "github.com/herpf/ds_store_parser_go"
+)
+
+func main() {
+	outputFormat := flag.String("output", "human", "Output format: 'human', 'jsonl', 'csv', or 'ndjson-flat'.")
+	fields := flag.String("fields", strings.Join(dsstore.AllFields, ","), "Comma-separated columns for 'csv'/'ndjson-flat' output, in order.")
+	timeFormatFlag := flag.String("time-format", "rfc3339", "Modification time format for 'csv'/'ndjson-flat' output: 'rfc3339', 'unix', or 'unix-nano'.")
+	flag.Parse()
+
+	// The first argument after the flags is the filename
+	filename := ".DS_Store"
+	if flag.NArg() > 0 {
+		filename = flag.Arg(0)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file '%s': %v\n", filename, err)
+		os.Exit(1)
+	}
+
+	dsStore, err := dsstore.NewDSStore(content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Choose output format based on the flag
+	switch *outputFormat {
+	case "jsonl":
+		dsStore.PrintJSONL()
+	case "human":
+		dsStore.PrintHumanReadable()
+	case "csv", "ndjson-flat":
+		if err := writeFlat(dsStore, *outputFormat, *fields, *timeFormatFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid output format '%s'. Please use 'human', 'jsonl', 'csv', or 'ndjson-flat'.\n", *outputFormat)
+		os.Exit(1)
+	}
+}
+
+// writeFlat drives dsStore through the requested flat Encoder (csv or
+// ndjson-flat), both of which consume records via Walk so they share the
+// same traversal the other output modes do.
+func writeFlat(dsStore *dsstore.DSStore, format, fieldsFlag, timeFormatFlag string) error {
+	timeFormat, err := dsstore.ParseTimeFormat(timeFormatFlag)
+	if err != nil {
+		return err
+	}
+
+	var selected []string
+	for _, f := range strings.Split(fieldsFlag, ",") {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			selected = append(selected, f)
+		}
+	}
+	if err := dsstore.ValidateFields(selected); err != nil {
+		return err
+	}
+
+	var enc dsstore.Encoder
+	switch format {
+	case "csv":
+		enc = dsstore.NewCSVEncoder(os.Stdout, selected, timeFormat)
+	case "ndjson-flat":
+		enc = dsstore.NewNDJSONFlatEncoder(os.Stdout, selected, timeFormat)
+	}
+
+	walkErr := dsStore.Walk(func(filename, structID, dataType string, value interface{}) error {
+		return enc.Encode(dsstore.Record{Filename: filename, StructID: structID, DataType: dataType, Value: value})
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return enc.Close()
+}
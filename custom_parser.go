@@ -1,11 +1,10 @@
-// main.go
-package main
+// custom_parser.go
+package dsstore
 
 import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
-	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -19,17 +18,34 @@ import (
 
 // DSStore holds the entire parsed structure of the .DS_Store file.
 type DSStore struct {
-	content []byte
-	reader  *bytes.Reader
-	records map[string]map[string]interface{}
+	content    []byte
+	reader     *bytes.Reader
+	records    map[string]map[string]interface{}
+	opts       ParseOptions
+	numBlocks  uint32
+	visited    map[uint32]bool
+	numRecords int
 }
 
-// NewDSStore creates and parses a DS_Store structure from a byte slice.
+// NewDSStore creates and parses a DS_Store structure from a byte slice,
+// using DefaultParseOptions' limits.
 func NewDSStore(content []byte) (*DSStore, error) {
+	return NewDSStoreWithOptions(content, DefaultParseOptions())
+}
+
+// NewDSStoreWithOptions is NewDSStore with caller-supplied limits on how
+// deep the B-tree may be followed and how many records may be read, for
+// callers parsing untrusted files.
+func NewDSStoreWithOptions(content []byte, opts ParseOptions) (*DSStore, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
 	d := &DSStore{
 		content: content,
 		reader:  bytes.NewReader(content),
 		records: make(map[string]map[string]interface{}),
+		opts:    opts,
+		visited: make(map[uint32]bool),
 	}
 	if err := d.parse(); err != nil {
 		return nil, err
@@ -51,42 +67,68 @@ func (d *DSStore) readUint64() (uint64, error) {
 	return val, err
 }
 
-// parse is the main parsing entrypoint.
+// parse is the main parsing entrypoint. It builds d.records by visiting
+// every record in the tree through parseNode; see Walk for a variant that
+// streams records to a callback instead of accumulating them in a map.
 func (d *DSStore) parse() error {
+	allocatorOffset, rootNodeID, err := d.locateRoot()
+	if err != nil {
+		return err
+	}
+	visit := func(filename, structID, dataType string, value interface{}) error {
+		if _, ok := d.records[filename]; !ok {
+			d.records[filename] = make(map[string]interface{})
+		}
+		d.records[filename][structID] = value
+		return nil
+	}
+	return d.parseNode(allocatorOffset, rootNodeID, visit, 0)
+}
+
+// locateRoot walks the file header, the TOC, and the DSDB master block to
+// find the B-tree's root node, returning the allocator offset needed to
+// resolve any blockID and the root node's own blockID.
+func (d *DSStore) locateRoot() (allocatorOffset uint32, rootNodeID uint32, err error) {
 	d.reader.Seek(4, io.SeekStart) // Skip alignment bytes
 	magic, err := d.readUint32()
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	if magic != 0x42756431 { // 'Bud1'
 		fmt.Fprintln(os.Stderr, "Warning: File magic number is not 'Bud1'. This may not be a valid .DS_Store file.")
 	}
 
-	allocatorOffset, err := d.readUint32()
+	allocatorOffset, err = d.readUint32()
 	if err != nil {
-		return err
+		return 0, 0, err
+	}
+
+	d.reader.Seek(int64(allocatorOffset), io.SeekStart)
+	d.numBlocks, err = d.readUint32()
+	if err != nil {
+		return 0, 0, err
 	}
 
 	tocOffset := int64(allocatorOffset) + 4 + 1032 // 0x408 offset
 	d.reader.Seek(tocOffset, io.SeekStart)
 	numTocEntries, err := d.readUint32()
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	var masterID uint32 = 0
 	for i := 0; i < int(numTocEntries); i++ {
 		keyLenByte, err := d.reader.ReadByte()
 		if err != nil {
-			return err
+			return 0, 0, err
 		}
 		key := make([]byte, keyLenByte)
-		if _, err := d.reader.Read(key); err != nil {
-			return err
+		if _, err := io.ReadFull(d.reader, key); err != nil {
+			return 0, 0, err
 		}
 		val, err := d.readUint32()
 		if err != nil {
-			return err
+			return 0, 0, err
 		}
 		if string(key) == "DSDB" {
 			masterID = val
@@ -94,24 +136,27 @@ func (d *DSStore) parse() error {
 		}
 	}
 	if masterID == 0 {
-		return fmt.Errorf("could not find 'DSDB' master block in the allocator")
+		return 0, 0, fmt.Errorf("could not find 'DSDB' master block in the allocator")
 	}
 
 	masterBlockOffset, _, err := d.getBlockInfo(allocatorOffset, masterID)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	d.reader.Seek(masterBlockOffset, io.SeekStart)
-	rootNodeID, err := d.readUint32()
+	rootNodeID, err = d.readUint32()
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
-	return d.parseNode(allocatorOffset, rootNodeID)
+	return allocatorOffset, rootNodeID, nil
 }
 
 // getBlockInfo calculates the offset and size of a data block.
 func (d *DSStore) getBlockInfo(allocatorOffset, blockID uint32) (offset int64, size uint32, err error) {
+	if blockID >= d.numBlocks {
+		return 0, 0, fmt.Errorf("block ID %d is out of range (allocator only has %d blocks)", blockID, d.numBlocks)
+	}
 	offsetsTableStart := int64(allocatorOffset) + 4 + 8
 	blockInfoOffset := offsetsTableStart + int64(blockID)*4
 
@@ -129,8 +174,26 @@ func (d *DSStore) getBlockInfo(allocatorOffset, blockID uint32) (offset int64, s
 	return offset, size, nil
 }
 
-// parseNode recursively parses a B-Tree node.
-func (d *DSStore) parseNode(allocatorOffset, nodeID uint32) error {
+// parseNode recursively parses a B-Tree node, calling visit for every
+// record it reads instead of accumulating them itself. parse uses a visit
+// callback that fills in d.records; Walk uses one that calls straight
+// through to its caller, so neither mode has to duplicate the traversal.
+// If visit returns ErrStopWalk, parseNode stops reading further records and
+// children and returns ErrStopWalk to its own caller so the whole recursion
+// unwinds without visiting anything else.
+//
+// depth and d.visited guard against a crafted file whose B-tree is
+// unreasonably deep or contains a cycle between child pointers; d.opts
+// bounds both depth and the total number of records read.
+func (d *DSStore) parseNode(allocatorOffset, nodeID uint32, visit VisitFunc, depth int) error {
+	if depth > d.opts.MaxDepth {
+		return fmt.Errorf("B-tree depth exceeds MaxDepth (%d)", d.opts.MaxDepth)
+	}
+	if d.visited[nodeID] {
+		return fmt.Errorf("cycle detected: block %d was already visited", nodeID)
+	}
+	d.visited[nodeID] = true
+
 	nodeOffset, _, err := d.getBlockInfo(allocatorOffset, nodeID)
 	if err != nil {
 		return err
@@ -149,6 +212,10 @@ func (d *DSStore) parseNode(allocatorOffset, nodeID uint32) error {
 	var childrenToParse []uint32
 
 	for i := 0; i < int(numRecords); i++ {
+		if d.numRecords >= d.opts.MaxRecords {
+			return fmt.Errorf("record count exceeds MaxRecords (%d)", d.opts.MaxRecords)
+		}
+
 		if rightmostChildID != 0 {
 			childID, err := d.readUint32()
 			if err != nil {
@@ -161,9 +228,9 @@ func (d *DSStore) parseNode(allocatorOffset, nodeID uint32) error {
 		if err != nil {
 			return err
 		}
-		utf16beBytes := make([]byte, nameLen*2)
-		if _, err := d.reader.Read(utf16beBytes); err != nil {
-			return err
+		utf16beBytes, err := d.readBounded(uint64(nameLen) * 2)
+		if err != nil {
+			return fmt.Errorf("filename: %w", err)
 		}
 		utf8Decoder := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
 		filenameBytes, _, err := transform.Bytes(utf8Decoder, utf16beBytes)
@@ -173,13 +240,13 @@ func (d *DSStore) parseNode(allocatorOffset, nodeID uint32) error {
 		filename := string(filenameBytes)
 
 		structBytes := make([]byte, 4)
-		if _, err := d.reader.Read(structBytes); err != nil {
+		if _, err := io.ReadFull(d.reader, structBytes); err != nil {
 			return err
 		}
 		structID := string(structBytes)
 
 		typeBytes := make([]byte, 4)
-		if _, err := d.reader.Read(typeBytes); err != nil {
+		if _, err := io.ReadFull(d.reader, typeBytes); err != nil {
 			return err
 		}
 		dataType := string(typeBytes)
@@ -189,26 +256,42 @@ func (d *DSStore) parseNode(allocatorOffset, nodeID uint32) error {
 			fmt.Fprintf(os.Stderr, "Warning: skipping record for '%s' due to parse error: %v\n", filename, err)
 			continue
 		}
+		d.numRecords++
 
-		if _, ok := d.records[filename]; !ok {
-			d.records[filename] = make(map[string]interface{})
+		if err := visit(filename, structID, dataType, decodeStructValue(structID, data)); err != nil {
+			return err
 		}
-		d.records[filename][structID] = data
 	}
 
 	for _, childID := range childrenToParse {
-		if err := d.parseNode(allocatorOffset, childID); err != nil {
+		if err := d.parseNode(allocatorOffset, childID, visit, depth+1); err != nil {
 			return err
 		}
 	}
 	if rightmostChildID != 0 {
-		if err := d.parseNode(allocatorOffset, rightmostChildID); err != nil {
+		if err := d.parseNode(allocatorOffset, rightmostChildID, visit, depth+1); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// readBounded reads exactly n bytes, refusing to allocate or read past
+// what's actually left in the reader so a bogus length prefix from a
+// crafted file can't be used to drive an oversized allocation. n is a
+// uint64 rather than the uint32 most callers derive it from so that
+// doubling a length for UTF-16 byte counts can't silently wrap around.
+func (d *DSStore) readBounded(n uint64) ([]byte, error) {
+	if n > uint64(d.reader.Len()) {
+		return nil, fmt.Errorf("length %d exceeds %d remaining bytes", n, d.reader.Len())
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.reader, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
 // parseData reads a value from the stream based on the provided data type string.
 func (d *DSStore) parseData(dataType string) (interface{}, error) {
 	switch dataType {
@@ -221,15 +304,15 @@ func (d *DSStore) parseData(dataType string) (interface{}, error) {
 		return d.readUint64()
 	case "type":
 		buf := make([]byte, 4)
-		_, err := d.reader.Read(buf)
+		_, err := io.ReadFull(d.reader, buf)
 		return string(buf), err
 	case "ustr":
 		strLen, err := d.readUint32()
 		if err != nil {
 			return nil, err
 		}
-		utf16beBytes := make([]byte, strLen*2)
-		if _, err := d.reader.Read(utf16beBytes); err != nil {
+		utf16beBytes, err := d.readBounded(uint64(strLen) * 2)
+		if err != nil {
 			return nil, err
 		}
 		utf8Decoder := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
@@ -240,16 +323,14 @@ func (d *DSStore) parseData(dataType string) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-		blob := make([]byte, blobLen)
-		_, err = d.reader.Read(blob)
-		return blob, err
+		return d.readBounded(uint64(blobLen))
 	default:
 		return nil, fmt.Errorf("unrecognized data type '%s'", dataType)
 	}
 }
 
-// printHumanReadable formats and prints the parsed records in a human-friendly format.
-func (d *DSStore) printHumanReadable() {
+// PrintHumanReadable formats and prints the parsed records in a human-friendly format.
+func (d *DSStore) PrintHumanReadable() {
 	for filename, properties := range d.records {
 		fmt.Println(filename)
 		for key, val := range properties {
@@ -272,14 +353,13 @@ func (d *DSStore) printHumanReadable() {
 					}
 				}
 			case "bwsp", "lsvp", "icvp":
-				if blob, ok := val.([]byte); ok && bytes.HasPrefix(blob, []byte("bplist")) {
-					var plistData interface{}
-					decoder := plist.NewDecoder(bytes.NewReader(blob))
-					if err := decoder.Decode(&plistData); err == nil {
-						xmlBytes, err := plist.MarshalIndent(plistData, plist.XMLFormat, "\t\t")
-						if err == nil {
-							output = fmt.Sprintf("%s (Property List):\n\t\t%s", key, strings.ReplaceAll(string(xmlBytes), "\n", "\n\t\t"))
-						}
+				// decodeStructValue already turned any bplist-prefixed blob
+				// into a Go value during parsing; re-marshal it as XML for
+				// display. If decoding failed, val is still the raw blob.
+				if _, ok := val.([]byte); !ok {
+					xmlBytes, err := plist.MarshalIndent(val, plist.XMLFormat, "\t\t")
+					if err == nil {
+						output = fmt.Sprintf("%s (Property List):\n\t\t%s", key, strings.ReplaceAll(string(xmlBytes), "\n", "\n\t\t"))
 					}
 				}
 			}
@@ -298,8 +378,8 @@ func (d *DSStore) printHumanReadable() {
 	}
 }
 
-// printJSONL formats and prints the parsed records as one JSON object per line.
-func (d *DSStore) printJSONL() {
+// PrintJSONL formats and prints the parsed records as one JSON object per line.
+func (d *DSStore) PrintJSONL() {
 	encoder := json.NewEncoder(os.Stdout)
 	for filename, properties := range d.records {
 		// Create a new map for the JSON object to ensure a consistent structure
@@ -312,37 +392,3 @@ func (d *DSStore) printJSONL() {
 		}
 	}
 }
-
-func main() {
-	outputFormat := flag.String("output", "human", "Output format: 'human' for readable text, 'jsonl' for JSON Lines.")
-	flag.Parse()
-
-	// The first argument after the flags is the filename
-	filename := ".DS_Store"
-	if flag.NArg() > 0 {
-		filename = flag.Arg(0)
-	}
-
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading file '%s': %v\n", filename, err)
-		os.Exit(1)
-	}
-
-	dsStore, err := NewDSStore(content)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Choose output format based on the flag
-	switch *outputFormat {
-	case "jsonl":
-		dsStore.printJSONL()
-	case "human":
-		dsStore.printHumanReadable()
-	default:
-		fmt.Fprintf(os.Stderr, "Error: invalid output format '%s'. Please use 'human' or 'jsonl'.\n", *outputFormat)
-		os.Exit(1)
-	}
-}
@@ -0,0 +1,87 @@
+// dsstorefs.go
+package dsstorefs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+
+	dsstore "github.com/herpf/ds_store_parser_go"
+)
+
+// ScanResult is one Finder metadata record recovered from a .DS_Store file
+// found somewhere under a scanned fs.FS, annotated with where it came from
+// and whether the file it describes is still there.
+type ScanResult struct {
+	DSStorePath   string // path of the .DS_Store file itself, relative to the scanned root
+	Dir           string // directory the .DS_Store lives in, and whose contents it describes
+	Filename      string
+	StructID      string
+	DataType      string
+	Value         interface{}
+	SiblingExists bool // whether Dir/Filename still exists in the scanned fs.FS
+}
+
+// ScanError records a single .DS_Store file ScanFS found but could not read
+// or parse.
+type ScanError struct {
+	DSStorePath string // path of the offending .DS_Store, relative to the scanned root
+	Err         error
+}
+
+func (e ScanError) Error() string {
+	return fmt.Sprintf("%s: %v", e.DSStorePath, e.Err)
+}
+
+// ScanFS walks root (an os.DirFS, a *zip.Reader, a tar archive wrapped as an
+// fs.FS, ...) looking for .DS_Store files, parses each one it finds via
+// dsstore.WalkDSStore, and returns every record it contains annotated with
+// the directory it was found in and whether the sibling file it describes
+// is still present. A .DS_Store that can't be read or parsed is recorded in
+// the returned []ScanError rather than aborting the scan: a truncated or
+// hostile store found among thousands of others shouldn't cost the rest of
+// the results. The returned error is reserved for failures walking the
+// filesystem tree itself (e.g. a directory that can't be listed).
+func ScanFS(root fs.FS) ([]ScanResult, []ScanError, error) {
+	var results []ScanResult
+	var scanErrs []ScanError
+	err := fs.WalkDir(root, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != ".DS_Store" {
+			return nil
+		}
+
+		content, err := fs.ReadFile(root, p)
+		if err != nil {
+			scanErrs = append(scanErrs, ScanError{DSStorePath: p, Err: fmt.Errorf("read: %w", err)})
+			return nil
+		}
+
+		dir := path.Dir(p)
+		walkErr := dsstore.WalkDSStore(content, func(filename, structID, dataType string, value interface{}) error {
+			siblingPath := filename
+			if dir != "." {
+				siblingPath = path.Join(dir, filename)
+			}
+			_, statErr := fs.Stat(root, siblingPath)
+
+			results = append(results, ScanResult{
+				DSStorePath:   p,
+				Dir:           dir,
+				Filename:      filename,
+				StructID:      structID,
+				DataType:      dataType,
+				Value:         value,
+				SiblingExists: statErr == nil,
+			})
+			return nil
+		})
+		if walkErr != nil {
+			scanErrs = append(scanErrs, ScanError{DSStorePath: p, Err: fmt.Errorf("parse: %w", walkErr)})
+		}
+		return nil
+	})
+	return results, scanErrs, err
+}
@@ -0,0 +1,80 @@
+// dsstorefs_test.go
+package dsstorefs
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	dsstore "github.com/herpf/ds_store_parser_go"
+)
+
+func buildDSStore(t *testing.T) []byte {
+	t.Helper()
+	b := dsstore.NewDSStoreBuilder()
+	if err := b.AddRecord("present.txt", "vSrn", "long", uint32(1)); err != nil {
+		t.Fatalf("AddRecord: %v", err)
+	}
+	if err := b.AddRecord("gone.txt", "vSrn", "long", uint32(1)); err != nil {
+		t.Fatalf("AddRecord: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestScanFS(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"photos/.DS_Store":    {Data: buildDSStore(t)},
+		"photos/present.txt":  {Data: []byte("hi")},
+		// "photos/gone.txt" intentionally absent
+	}
+
+	results, scanErrs, err := ScanFS(mapFS)
+	if err != nil {
+		t.Fatalf("ScanFS: %v", err)
+	}
+	if len(scanErrs) != 0 {
+		t.Fatalf("ScanFS returned unexpected errors: %v", scanErrs)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ScanFS returned %d results, want 2", len(results))
+	}
+
+	bySibling := map[string]bool{}
+	for _, r := range results {
+		if r.Dir != "photos" {
+			t.Errorf("result %+v has Dir = %q, want photos", r, r.Dir)
+		}
+		bySibling[r.Filename] = r.SiblingExists
+	}
+	if !bySibling["present.txt"] {
+		t.Errorf("present.txt should have SiblingExists = true")
+	}
+	if bySibling["gone.txt"] {
+		t.Errorf("gone.txt should have SiblingExists = false")
+	}
+}
+
+// TestScanFSContinuesPastBadStore checks that one unparseable .DS_Store
+// doesn't stop ScanFS from reporting results for the rest of the tree.
+func TestScanFSContinuesPastBadStore(t *testing.T) {
+	mapFS := fstest.MapFS{
+		"broken/.DS_Store":   {Data: []byte("not a DS_Store")},
+		"photos/.DS_Store":   {Data: buildDSStore(t)},
+		"photos/present.txt": {Data: []byte("hi")},
+	}
+
+	results, scanErrs, err := ScanFS(mapFS)
+	if err != nil {
+		t.Fatalf("ScanFS: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ScanFS returned %d results, want 2 (from the valid store)", len(results))
+	}
+	if len(scanErrs) != 1 || scanErrs[0].DSStorePath != "broken/.DS_Store" {
+		t.Fatalf("ScanFS returned scanErrs = %+v, want one error for broken/.DS_Store", scanErrs)
+	}
+}
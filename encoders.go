@@ -0,0 +1,238 @@
+// encoders.go
+package dsstore
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// TimeFormat selects how Encoder implementations render a record's
+// modification time.
+type TimeFormat int
+
+const (
+	TimeFormatRFC3339 TimeFormat = iota
+	TimeFormatUnix
+	TimeFormatUnixNano
+)
+
+// ParseTimeFormat parses the -time-format flag value.
+func ParseTimeFormat(s string) (TimeFormat, error) {
+	switch s {
+	case "", "rfc3339":
+		return TimeFormatRFC3339, nil
+	case "unix":
+		return TimeFormatUnix, nil
+	case "unix-nano":
+		return TimeFormatUnixNano, nil
+	default:
+		return 0, fmt.Errorf("unrecognized time format %q (want rfc3339, unix, or unix-nano)", s)
+	}
+}
+
+func (f TimeFormat) render(t time.Time) string {
+	switch f {
+	case TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeFormatUnixNano:
+		return strconv.FormatInt(t.UnixNano(), 10)
+	default:
+		return t.Format(time.RFC3339)
+	}
+}
+
+// AllFields lists every column the flat encoders know how to produce, in
+// their default order. Pass a subset (in any order) to NewCSVEncoder or
+// NewNDJSONFlatEncoder to select and reorder columns.
+var AllFields = []string{
+	"filename",
+	"struct_id",
+	"data_type",
+	"value_string",
+	"value_int",
+	"value_blob_hex",
+	"modification_time",
+	"window_x",
+	"window_y",
+}
+
+// ValidateFields checks that every name in fields is one AllFields lists,
+// returning an error naming the first one that isn't. Callers building a
+// CSVEncoder or NDJSONFlatEncoder from user-supplied column names (e.g. the
+// CLI's -fields flag) should call this first: flatRow.field silently
+// returns "" for an unrecognized name, which would otherwise ship a
+// silently-blank column to whatever's ingesting the output.
+func ValidateFields(fields []string) error {
+	known := make(map[string]bool, len(AllFields))
+	for _, f := range AllFields {
+		known[f] = true
+	}
+	for _, f := range fields {
+		if !known[f] {
+			return fmt.Errorf("unrecognized field %q (want one of %v)", f, AllFields)
+		}
+	}
+	return nil
+}
+
+// flatRow is Record flattened into the typed, SIEM-friendly columns listed
+// in AllFields. Exactly one of ValueString/ValueInt/ValueBlobHex/
+// ModificationTime/(WindowX,WindowY) is populated per record, depending on
+// its Go type; the rest are left at their zero value.
+type flatRow struct {
+	Filename         string
+	StructID         string
+	DataType         string
+	ValueString      string
+	ValueInt         *int64
+	ValueBlobHex     string
+	ModificationTime *time.Time
+	WindowX          *int64
+	WindowY          *int64
+}
+
+// flattenRecord converts rec's Value into flatRow's typed columns. "moDD"
+// and "modD" dutc values are recognized and converted to a real time.Time
+// using the same Mac HFS epoch conversion PrintHumanReadable uses;
+// everything else falls back on its Go type.
+func flattenRecord(rec Record) flatRow {
+	row := flatRow{Filename: rec.Filename, StructID: rec.StructID, DataType: rec.DataType}
+	switch v := rec.Value.(type) {
+	case bool:
+		row.ValueString = strconv.FormatBool(v)
+	case uint32:
+		n := int64(v)
+		row.ValueInt = &n
+	case uint64:
+		if rec.StructID == "moDD" || rec.StructID == "modD" {
+			seconds := int64(v/65536) - 2082844800
+			t := time.Unix(seconds, 0).UTC()
+			row.ModificationTime = &t
+		} else {
+			n := int64(v)
+			row.ValueInt = &n
+		}
+	case string:
+		row.ValueString = v
+	case []byte:
+		row.ValueBlobHex = hex.EncodeToString(v)
+	case IlocValue:
+		x, y := int64(v.X), int64(v.Y)
+		row.WindowX, row.WindowY = &x, &y
+	case Fwi0Value:
+		x, y := int64(v.Left), int64(v.Top)
+		row.WindowX, row.WindowY = &x, &y
+	default:
+		row.ValueString = fmt.Sprintf("%v", v)
+	}
+	return row
+}
+
+func (r flatRow) field(name string, tf TimeFormat) string {
+	switch name {
+	case "filename":
+		return r.Filename
+	case "struct_id":
+		return r.StructID
+	case "data_type":
+		return r.DataType
+	case "value_string":
+		return r.ValueString
+	case "value_int":
+		if r.ValueInt != nil {
+			return strconv.FormatInt(*r.ValueInt, 10)
+		}
+	case "value_blob_hex":
+		return r.ValueBlobHex
+	case "modification_time":
+		if r.ModificationTime != nil {
+			return tf.render(*r.ModificationTime)
+		}
+	case "window_x":
+		if r.WindowX != nil {
+			return strconv.FormatInt(*r.WindowX, 10)
+		}
+	case "window_y":
+		if r.WindowY != nil {
+			return strconv.FormatInt(*r.WindowY, 10)
+		}
+	}
+	return ""
+}
+
+// Encoder writes one Record at a time in some serialized form. Package
+// dsstore ships CSVEncoder and NDJSONFlatEncoder; third-party code can
+// implement Encoder to add output formats without touching the CLI.
+type Encoder interface {
+	Encode(rec Record) error
+	Close() error
+}
+
+// CSVEncoder writes records as CSV rows with a fixed, flat schema: one row
+// per (filename, structID) pair, columns selected by Fields.
+type CSVEncoder struct {
+	w           *csv.Writer
+	fields      []string
+	timeFormat  TimeFormat
+	wroteHeader bool
+}
+
+// NewCSVEncoder returns a CSVEncoder writing to w. fields selects and
+// orders the output columns; pass AllFields for the default set.
+func NewCSVEncoder(w io.Writer, fields []string, timeFormat TimeFormat) *CSVEncoder {
+	return &CSVEncoder{w: csv.NewWriter(w), fields: fields, timeFormat: timeFormat}
+}
+
+func (e *CSVEncoder) Encode(rec Record) error {
+	if !e.wroteHeader {
+		if err := e.w.Write(e.fields); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	row := flattenRecord(rec)
+	values := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		values[i] = row.field(f, e.timeFormat)
+	}
+	return e.w.Write(values)
+}
+
+func (e *CSVEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// NDJSONFlatEncoder writes records as newline-delimited JSON objects using
+// the same flat schema as CSVEncoder, rather than jsonl's nested
+// filename->properties map.
+type NDJSONFlatEncoder struct {
+	enc        *json.Encoder
+	fields     []string
+	timeFormat TimeFormat
+}
+
+// NewNDJSONFlatEncoder returns an NDJSONFlatEncoder writing to w. fields
+// selects which columns appear in each JSON object; pass AllFields for the
+// default set.
+func NewNDJSONFlatEncoder(w io.Writer, fields []string, timeFormat TimeFormat) *NDJSONFlatEncoder {
+	return &NDJSONFlatEncoder{enc: json.NewEncoder(w), fields: fields, timeFormat: timeFormat}
+}
+
+func (e *NDJSONFlatEncoder) Encode(rec Record) error {
+	row := flattenRecord(rec)
+	obj := make(map[string]string, len(e.fields))
+	for _, f := range e.fields {
+		obj[f] = row.field(f, e.timeFormat)
+	}
+	return e.enc.Encode(obj)
+}
+
+func (e *NDJSONFlatEncoder) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+// encoders_test.go
+package dsstore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCSVEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewCSVEncoder(&buf, []string{"filename", "struct_id", "value_int"}, TimeFormatRFC3339)
+	if err := enc.Encode(Record{Filename: "a.txt", StructID: "vSrn", DataType: "long", Value: uint32(7)}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2 (header + row): %q", len(lines), buf.String())
+	}
+	if lines[0] != "filename,struct_id,value_int" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if lines[1] != "a.txt,vSrn,7" {
+		t.Errorf("row = %q", lines[1])
+	}
+}
+
+func TestNDJSONFlatEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewNDJSONFlatEncoder(&buf, []string{"filename", "value_blob_hex"}, TimeFormatRFC3339)
+	if err := enc.Encode(Record{Filename: "b.txt", StructID: "Iloc", DataType: "blob", Value: []byte{0xde, 0xad}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got := strings.TrimSpace(buf.String())
+	want := `{"filename":"b.txt","value_blob_hex":"dead"}`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseTimeFormat(t *testing.T) {
+	if _, err := ParseTimeFormat("bogus"); err == nil {
+		t.Error("ParseTimeFormat(bogus) should error")
+	}
+	if tf, err := ParseTimeFormat("unix"); err != nil || tf != TimeFormatUnix {
+		t.Errorf("ParseTimeFormat(unix) = %v, %v", tf, err)
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	if err := ValidateFields(AllFields); err != nil {
+		t.Errorf("ValidateFields(AllFields) = %v, want nil", err)
+	}
+	if err := ValidateFields([]string{"filename", "value_itn"}); err == nil {
+		t.Error("ValidateFields with a typo'd field name should error")
+	}
+	if err := ValidateFields(nil); err != nil {
+		t.Errorf("ValidateFields(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,27 @@
+// fuzz_test.go
+package dsstore
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzParseDSStore feeds arbitrary bytes through NewDSStore and asserts
+// only that it never panics; a malformed or hostile .DS_Store should
+// surface as an error, not a crash or a runaway allocation.
+func FuzzParseDSStore(f *testing.F) {
+	f.Add([]byte(nil))
+	f.Add([]byte("Bud1"))
+
+	b := NewDSStoreBuilder()
+	b.AddRecord("seed.txt", "vSrn", "long", uint32(1))
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		f.Fatalf("Write seed corpus: %v", err)
+	}
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = NewDSStore(data)
+	})
+}
@@ -0,0 +1,35 @@
+// options.go
+package dsstore
+
+import "fmt"
+
+// ParseOptions bounds how much work NewDSStore (and WalkDSStore) will do on
+// a single file, so a crafted .DS_Store can't OOM or hang the process.
+type ParseOptions struct {
+	// MaxDepth caps how many levels of B-tree nodes will be followed from
+	// the root. A real .DS_Store is rarely more than 3-4 levels deep even
+	// with millions of records, since each node holds many entries.
+	MaxDepth int
+	// MaxRecords caps the total number of records read across the whole
+	// tree.
+	MaxRecords int
+}
+
+// DefaultParseOptions returns the limits NewDSStore applies when no
+// ParseOptions are given explicitly.
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{
+		MaxDepth:   64,
+		MaxRecords: 5_000_000,
+	}
+}
+
+func (o ParseOptions) validate() error {
+	if o.MaxDepth <= 0 {
+		return fmt.Errorf("ParseOptions.MaxDepth must be positive, got %d", o.MaxDepth)
+	}
+	if o.MaxRecords <= 0 {
+		return fmt.Errorf("ParseOptions.MaxRecords must be positive, got %d", o.MaxRecords)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// options_test.go
+package dsstore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOversizedLengthPrefixErrorsInsteadOfAllocating(t *testing.T) {
+	// A crafted "blob" length prefix claiming far more data than the
+	// reader actually has left must error, not attempt a giant allocation.
+	d := &DSStore{reader: bytes.NewReader([]byte{0x01, 0x02})}
+	if _, err := d.readBounded(1 << 32); err == nil {
+		t.Fatal("readBounded with an oversized length should error")
+	}
+}
+
+func TestParseOptionsValidate(t *testing.T) {
+	if err := (ParseOptions{}).validate(); err == nil {
+		t.Error("zero-value ParseOptions should fail validation")
+	}
+	if err := DefaultParseOptions().validate(); err != nil {
+		t.Errorf("DefaultParseOptions() should validate, got %v", err)
+	}
+}
+
+func TestWalkDSStoreWithOptionsRejectsTinyMaxRecords(t *testing.T) {
+	b := NewDSStoreBuilder()
+	for i := 0; i < 5; i++ {
+		name := string(rune('a' + i))
+		if err := b.AddRecord(name, "vSrn", "long", uint32(i)); err != nil {
+			t.Fatalf("AddRecord: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	err := WalkDSStoreWithOptions(buf.Bytes(), ParseOptions{MaxDepth: 64, MaxRecords: 1}, func(string, string, string, interface{}) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error when records exceed MaxRecords")
+	}
+}
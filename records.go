@@ -0,0 +1,131 @@
+// records.go
+package dsstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"howett.net/plist"
+)
+
+// FinderRecord is a typed view over everything NewDSStore parsed for a
+// single filename: one entry in Properties per structID, decoded as far as
+// decodeStructValue can take it.
+type FinderRecord struct {
+	Filename   string
+	Properties map[string]interface{}
+}
+
+// Record looks up everything parsed for filename, exposing decoded values
+// (property lists, IlocValue, Fwi0Value, ...) instead of requiring callers
+// to type-assert d.records themselves.
+func (d *DSStore) Record(filename string) (*FinderRecord, bool) {
+	props, ok := d.records[filename]
+	if !ok {
+		return nil, false
+	}
+	return &FinderRecord{Filename: filename, Properties: props}, true
+}
+
+// IlocValue is the decoded form of an "Iloc" record: the desktop icon
+// position for a file, in Finder's 16-byte {x, y, reserved} layout.
+type IlocValue struct {
+	X, Y uint32
+}
+
+// Fwi0Value is the decoded form of an "fwi0" record: a Finder window's
+// saved bounds and the view Finder should open it in.
+type Fwi0Value struct {
+	Top, Left, Bottom, Right int16
+	ViewType                 string
+}
+
+// BkgdValue is the decoded form of a "BKGD" record: a Finder window
+// background, tagged by Type ("ClrB" for a solid color, "PctB" for a
+// picture, "DefB" for the default). The type-specific fields after the tag
+// aren't interpreted further; Raw holds them unparsed.
+type BkgdValue struct {
+	Type string
+	Raw  []byte
+}
+
+// StructDecoder turns the raw value parseData produced for a record (a
+// []byte for "blob" fields) into a typed Go value.
+type StructDecoder func(raw interface{}) (interface{}, error)
+
+// StructRegistry maps structIDs with a known, documented binary layout to a
+// decoder for that layout. It only needs entries for structIDs whose value
+// parseData can't already represent faithfully (i.e. "blob" fields with a
+// fixed internal format); scalar structIDs like vSrn (long), cmmt/extn
+// (ustr), or moDD/modD (dutc) come out of parseData correctly typed already
+// and need no entry here. pict and icgo are also blob-valued but are left
+// undecoded: pict holds a full classic Mac OS alias record, and icgo's
+// internal layout isn't documented with enough confidence to risk silently
+// misreading it; both still come through as a raw []byte. Callers may
+// register additional decoders for structIDs this package doesn't know
+// about.
+var StructRegistry = map[string]StructDecoder{
+	"Iloc": decodeIloc,
+	"dilc": decodeIloc, // desktop icon location; same {x, y, reserved} layout as Iloc
+	"fwi0": decodeFwi0,
+	"BKGD": decodeBkgd,
+}
+
+func decodeIloc(raw interface{}) (interface{}, error) {
+	blob, ok := raw.([]byte)
+	if !ok || len(blob) < 8 {
+		return nil, fmt.Errorf("Iloc: expected a blob of at least 8 bytes, got %T", raw)
+	}
+	return IlocValue{
+		X: binary.BigEndian.Uint32(blob[0:4]),
+		Y: binary.BigEndian.Uint32(blob[4:8]),
+	}, nil
+}
+
+func decodeBkgd(raw interface{}) (interface{}, error) {
+	blob, ok := raw.([]byte)
+	if !ok || len(blob) < 4 {
+		return nil, fmt.Errorf("BKGD: expected a blob of at least 4 bytes, got %T", raw)
+	}
+	return BkgdValue{
+		Type: string(blob[0:4]),
+		Raw:  append([]byte(nil), blob[4:]...),
+	}, nil
+}
+
+func decodeFwi0(raw interface{}) (interface{}, error) {
+	blob, ok := raw.([]byte)
+	if !ok || len(blob) < 16 {
+		return nil, fmt.Errorf("fwi0: expected a blob of at least 16 bytes, got %T", raw)
+	}
+	return Fwi0Value{
+		Top:      int16(binary.BigEndian.Uint16(blob[0:2])),
+		Left:     int16(binary.BigEndian.Uint16(blob[2:4])),
+		Bottom:   int16(binary.BigEndian.Uint16(blob[4:6])),
+		Right:    int16(binary.BigEndian.Uint16(blob[6:8])),
+		ViewType: string(blob[8:12]),
+	}, nil
+}
+
+// decodeStructValue upgrades a raw parseData result into a more useful Go
+// value where possible. Any blob whose payload begins with the "bplist"
+// magic is decoded through howett.net/plist regardless of structID, since
+// that covers bwsp/icvp/lsvp today and any future blob-valued struct that
+// happens to carry a property list. Failing that, StructRegistry is
+// consulted for structIDs with a known fixed binary layout. If neither
+// applies, the raw value is returned unchanged.
+func decodeStructValue(structID string, value interface{}) interface{} {
+	if blob, ok := value.([]byte); ok && bytes.HasPrefix(blob, []byte("bplist")) {
+		var plistValue interface{}
+		if err := plist.NewDecoder(bytes.NewReader(blob)).Decode(&plistValue); err == nil {
+			return plistValue
+		}
+	}
+	if decode, ok := StructRegistry[structID]; ok {
+		if typed, err := decode(value); err == nil {
+			return typed
+		}
+	}
+	return value
+}
@@ -0,0 +1,56 @@
+// records_test.go
+package dsstore
+
+import "testing"
+
+func TestDecodeStructValue(t *testing.T) {
+	iloc := make([]byte, 16)
+	iloc[3] = 10 // X = 10
+	iloc[7] = 20 // Y = 20
+	got := decodeStructValue("Iloc", iloc)
+	v, ok := got.(IlocValue)
+	if !ok || v.X != 10 || v.Y != 20 {
+		t.Errorf("decodeStructValue(Iloc) = %#v, want IlocValue{10, 20}", got)
+	}
+
+	// A structID with no registry entry and no bplist magic passes through.
+	passthrough := decodeStructValue("vSrn", uint32(1))
+	if passthrough != uint32(1) {
+		t.Errorf("decodeStructValue(vSrn) = %v, want passthrough", passthrough)
+	}
+
+	// dilc shares Iloc's layout (desktop icon location).
+	dilc := make([]byte, 16)
+	dilc[3] = 5
+	dilc[7] = 6
+	got = decodeStructValue("dilc", dilc)
+	if v, ok := got.(IlocValue); !ok || v.X != 5 || v.Y != 6 {
+		t.Errorf("decodeStructValue(dilc) = %#v, want IlocValue{5, 6}", got)
+	}
+
+	// BKGD is tagged by a 4-byte type with the rest left raw.
+	bkgd := append([]byte("ClrB"), 0x10, 0x20)
+	got = decodeStructValue("BKGD", bkgd)
+	v2, ok := got.(BkgdValue)
+	if !ok || v2.Type != "ClrB" || string(v2.Raw) != "\x10\x20" {
+		t.Errorf("decodeStructValue(BKGD) = %#v, want BkgdValue{ClrB, [0x10 0x20]}", got)
+	}
+
+	// pict is intentionally left undecoded (no confidently-documented layout).
+	if got := decodeStructValue("pict", []byte("alias-data")); string(got.([]byte)) != "alias-data" {
+		t.Errorf("decodeStructValue(pict) = %v, want passthrough raw bytes", got)
+	}
+}
+
+func TestDSStoreRecord(t *testing.T) {
+	d := &DSStore{records: map[string]map[string]interface{}{
+		"foo.txt": {"vSrn": uint32(1)},
+	}}
+	rec, ok := d.Record("foo.txt")
+	if !ok || rec.Properties["vSrn"] != uint32(1) {
+		t.Fatalf("Record(foo.txt) = %#v, %v", rec, ok)
+	}
+	if _, ok := d.Record("missing.txt"); ok {
+		t.Errorf("Record(missing.txt) should not be found")
+	}
+}
@@ -0,0 +1,88 @@
+// walk.go
+package dsstore
+
+import (
+	"bytes"
+	"errors"
+)
+
+// VisitFunc is called once per record encountered while walking a
+// .DS_Store's B-tree, in the same (filename, structID, dataType, value)
+// shape parseNode already had in hand. Returning ErrStopWalk ends the walk
+// early without it being reported as a failure; any other non-nil error
+// aborts the walk and is returned to the caller unchanged.
+type VisitFunc func(filename, structID, dataType string, value interface{}) error
+
+// ErrStopWalk is a sentinel a VisitFunc can return to stop Walk or
+// WalkDSStore early, analogous to fs.SkipAll.
+var ErrStopWalk = errors.New("dsstore: stop walking")
+
+// Record is the channel-friendly counterpart to VisitFunc's arguments,
+// used by Records.
+type Record struct {
+	Filename string
+	StructID string
+	DataType string
+	Value    interface{}
+}
+
+// Walk re-traverses the store's B-tree, calling fn for every record
+// instead of returning the map NewDSStore already built. Unlike that map,
+// which keeps every record in memory for the life of the DSStore, Walk
+// (and the package-level WalkDSStore it's built on) never materializes
+// one; use it when d.records' footprint matters more than re-walking the
+// tree costs in CPU. It re-applies the same ParseOptions the DSStore was
+// constructed with.
+func (d *DSStore) Walk(fn VisitFunc) error {
+	return WalkDSStoreWithOptions(d.content, d.opts, fn)
+}
+
+// Records returns a channel of every record in the store, populated by a
+// background goroutine running Walk. The goroutine sends synchronously, so
+// a consumer that stops ranging over the channel before it's drained will
+// leak it; callers that need to bail out early should call Walk directly
+// and return ErrStopWalk from their VisitFunc instead.
+func (d *DSStore) Records() <-chan Record {
+	ch := make(chan Record)
+	go func() {
+		defer close(ch)
+		d.Walk(func(filename, structID, dataType string, value interface{}) error {
+			ch <- Record{filename, structID, dataType, value}
+			return nil
+		})
+	}()
+	return ch
+}
+
+// WalkDSStore parses content's B-tree and calls fn for every record as
+// it's read, without ever collecting them into a map the way NewDSStore
+// does. It's the low-memory entry point for pathologically large
+// .DS_Store files, where d.records would otherwise hold every record for
+// the life of the DSStore. It applies DefaultParseOptions' limits; use
+// WalkDSStoreWithOptions to change them.
+func WalkDSStore(content []byte, fn VisitFunc) error {
+	return WalkDSStoreWithOptions(content, DefaultParseOptions(), fn)
+}
+
+// WalkDSStoreWithOptions is WalkDSStore with caller-supplied limits on how
+// deep the B-tree may be followed and how many records may be read.
+func WalkDSStoreWithOptions(content []byte, opts ParseOptions, fn VisitFunc) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	d := &DSStore{
+		content: content,
+		reader:  bytes.NewReader(content),
+		opts:    opts,
+		visited: make(map[uint32]bool),
+	}
+	allocatorOffset, rootNodeID, err := d.locateRoot()
+	if err != nil {
+		return err
+	}
+	err = d.parseNode(allocatorOffset, rootNodeID, fn, 0)
+	if err == ErrStopWalk {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+// walk_test.go
+package dsstore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func buildSampleStore(t *testing.T) []byte {
+	t.Helper()
+	b := NewDSStoreBuilder()
+	if err := b.AddRecord("a.txt", "vSrn", "long", uint32(1)); err != nil {
+		t.Fatalf("AddRecord: %v", err)
+	}
+	if err := b.AddRecord("b.txt", "vSrn", "long", uint32(2)); err != nil {
+		t.Fatalf("AddRecord: %v", err)
+	}
+	if err := b.AddRecord("c.txt", "vSrn", "long", uint32(3)); err != nil {
+		t.Fatalf("AddRecord: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWalkVisitsEveryRecord(t *testing.T) {
+	content := buildSampleStore(t)
+	seen := map[string]uint32{}
+	err := WalkDSStore(content, func(filename, structID, dataType string, value interface{}) error {
+		if structID == "vSrn" {
+			seen[filename] = value.(uint32)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDSStore: %v", err)
+	}
+	want := map[string]uint32{"a.txt": 1, "b.txt": 2, "c.txt": 3}
+	for filename, v := range want {
+		if seen[filename] != v {
+			t.Errorf("seen[%s] = %v, want %v", filename, seen[filename], v)
+		}
+	}
+}
+
+func TestWalkStopsEarly(t *testing.T) {
+	content := buildSampleStore(t)
+	count := 0
+	err := WalkDSStore(content, func(filename, structID, dataType string, value interface{}) error {
+		count++
+		return ErrStopWalk
+	})
+	if err != nil {
+		t.Fatalf("WalkDSStore: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("visited %d records after ErrStopWalk, want 1", count)
+	}
+}
+
+func TestRecordsChannel(t *testing.T) {
+	d, err := NewDSStore(buildSampleStore(t))
+	if err != nil {
+		t.Fatalf("NewDSStore: %v", err)
+	}
+	n := 0
+	for range d.Records() {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("Records() yielded %d records, want 3", n)
+	}
+}
@@ -0,0 +1,411 @@
+// writer.go
+package dsstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"howett.net/plist"
+)
+
+// These constants mirror the on-disk layout that NewDSStore already knows
+// how to read: a fixed-size offset table (256 entries) follows the buddy
+// allocator's 12-byte header, and every block offset must satisfy
+// (offset-4) % 32 == 0 so it can be packed into the 27-bit offset field
+// alongside the 5-bit power-of-two size field.
+const (
+	dsHeaderSize          = 32
+	dsOffsetTableEntries  = 256
+	dsOffsetTableSize     = dsOffsetTableEntries * 4
+	dsNumFreeLists        = 32
+	dsMinBlockOrder       = 5 // 1<<5 == 32 bytes, the smallest allocation unit
+	dsMasterBlockSize     = 20
+	dsBTreePageSize uint32 = 0x1000
+	// dsTOCSize is the one-entry TOC Write always emits: a uint32 count, a
+	// 1-byte keylen, the 4-byte "DSDB" key, and a uint32 blockID.
+	dsTOCSize = 4 + 1 + 4 + 4
+)
+
+// builderRecord is a single (filename, structID) entry staged by a
+// DSStoreBuilder before it has been assigned a position in the B-tree.
+type builderRecord struct {
+	filename string
+	structID string
+	dataType string
+	value    interface{}
+}
+
+// DSStoreBuilder accumulates per-file Finder metadata records and emits a
+// parsable .DS_Store image via Write. Unlike DSStore, which only ever grows
+// a read-only map while parsing, the builder owns the writing side: it lays
+// out the buddy allocator's bookkeeping area, splits records across B-tree
+// leaf pages once a page would otherwise overflow, and encodes each value
+// back into the wire format parseData knows how to decode.
+type DSStoreBuilder struct {
+	records []builderRecord
+}
+
+// NewDSStoreBuilder returns an empty builder ready to accept records.
+func NewDSStoreBuilder() *DSStoreBuilder {
+	return &DSStoreBuilder{}
+}
+
+// AddRecord stages a single record for filename. dataType must be one of the
+// types parseData understands ("bool", "shor", "long", "comp", "dutc",
+// "type", "ustr", "blob"), and value must be the matching Go type (bool,
+// uint32, uint64, string, or []byte respectively).
+func (b *DSStoreBuilder) AddRecord(filename, structID, dataType string, value interface{}) error {
+	if len(structID) != 4 {
+		return fmt.Errorf("structID %q must be exactly 4 bytes", structID)
+	}
+	if len(dataType) != 4 {
+		return fmt.Errorf("dataType %q must be exactly 4 bytes", dataType)
+	}
+	if _, err := encodeFieldValue(dataType, value); err != nil {
+		return fmt.Errorf("record %s/%s: %w", filename, structID, err)
+	}
+	b.records = append(b.records, builderRecord{filename, structID, dataType, value})
+	return nil
+}
+
+// AddBplist stages a "blob" record whose payload is value marshaled to
+// binary property-list form, the encoding Finder uses for bwsp/icvp/lsvp
+// window-state blobs.
+func (b *DSStoreBuilder) AddBplist(filename, structID string, value interface{}) error {
+	raw, err := plist.Marshal(value, plist.BinaryFormat)
+	if err != nil {
+		return fmt.Errorf("marshal plist for %s/%s: %w", filename, structID, err)
+	}
+	return b.AddRecord(filename, structID, "blob", raw)
+}
+
+// SetIconLocation stages an "Iloc" record placing filename's desktop icon
+// at (x, y), matching the 16-byte {x, y, reserved} layout Finder writes.
+func (b *DSStoreBuilder) SetIconLocation(filename string, x, y uint32) error {
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint32(payload[0:4], x)
+	binary.BigEndian.PutUint32(payload[4:8], y)
+	return b.AddRecord(filename, "Iloc", "blob", payload)
+}
+
+// encodeFieldValue encodes value into the wire representation parseData
+// would decode for dataType, without the "blob"/"ustr" length prefix (that
+// prefix is added by encodeRecord, which also needs the encoded length).
+func encodeFieldValue(dataType string, value interface{}) ([]byte, error) {
+	switch dataType {
+	case "bool":
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("value must be bool, got %T", value)
+		}
+		if v {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case "shor", "long":
+		v, ok := value.(uint32)
+		if !ok {
+			return nil, fmt.Errorf("value must be uint32, got %T", value)
+		}
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, v)
+		return buf, nil
+	case "comp", "dutc":
+		v, ok := value.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("value must be uint64, got %T", value)
+		}
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, v)
+		return buf, nil
+	case "type":
+		v, ok := value.(string)
+		if !ok || len(v) != 4 {
+			return nil, fmt.Errorf("value must be a 4-byte string, got %T", value)
+		}
+		return []byte(v), nil
+	case "ustr":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value must be string, got %T", value)
+		}
+		return encodeUTF16BE(v)
+	case "blob":
+		v, ok := value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("value must be []byte, got %T", value)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unrecognized data type %q", dataType)
+	}
+}
+
+// encodeUTF16BE converts a Go string to big-endian UTF-16, the encoding
+// Finder uses for both B-tree record keys and "ustr" values.
+func encodeUTF16BE(s string) ([]byte, error) {
+	encoder := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder()
+	b, _, err := transform.Bytes(encoder, []byte(s))
+	return b, err
+}
+
+// encodeRecord serializes a single B-tree record: the UTF-16BE filename key,
+// its 4-byte structID and dataType, and the type-specific payload (with a
+// length prefix for "ustr" and "blob").
+func encodeRecord(r builderRecord) ([]byte, error) {
+	nameUTF16, err := encodeUTF16BE(r.filename)
+	if err != nil {
+		return nil, fmt.Errorf("encode filename %q: %w", r.filename, err)
+	}
+	if len(nameUTF16)%2 != 0 {
+		return nil, fmt.Errorf("encoded filename %q has odd byte length", r.filename)
+	}
+
+	var buf bytes.Buffer
+	var nameLen uint32 = uint32(len(nameUTF16) / 2)
+	binary.Write(&buf, binary.BigEndian, nameLen)
+	buf.Write(nameUTF16)
+	buf.WriteString(r.structID)
+	buf.WriteString(r.dataType)
+
+	payload, err := encodeFieldValue(r.dataType, r.value)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s/%s: %w", r.filename, r.structID, err)
+	}
+	switch r.dataType {
+	case "ustr":
+		binary.Write(&buf, binary.BigEndian, uint32(len(payload)/2))
+	case "blob":
+		binary.Write(&buf, binary.BigEndian, uint32(len(payload)))
+	}
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+// allocatedBlock is a block that has been assigned a blockID, an on-disk
+// offset, and a power-of-two size by the writer's buddy allocator.
+type allocatedBlock struct {
+	offset int64
+	order  uint32 // size = 1 << order
+	data   []byte
+}
+
+// allocatorBuilder hands out blockIDs and offsets the way the buddy
+// allocator described in getBlockInfo expects: every block's offset must
+// satisfy (offset-4) % 32 == 0, and its size is rounded up to the next
+// power of two (minimum 32 bytes). Freshly-built stores never reuse freed
+// space, so the free lists the allocator writes out are always empty; a
+// real Finder-maintained file reuses blocks via those lists as records are
+// edited in place, but a one-shot writer has nothing to free.
+type allocatorBuilder struct {
+	blocks []allocatedBlock
+	cursor int64
+}
+
+func newAllocatorBuilder(firstOffset int64) *allocatorBuilder {
+	return &allocatorBuilder{cursor: firstOffset}
+}
+
+func align32(off int64) int64 {
+	rem := (off - 4) % 32
+	if rem < 0 {
+		rem += 32
+	}
+	if rem != 0 {
+		off += 32 - rem
+	}
+	return off
+}
+
+func blockOrder(size int) uint32 {
+	order := uint32(dsMinBlockOrder)
+	for (uint32(1) << order) < uint32(size) {
+		order++
+	}
+	return order
+}
+
+// alloc appends data as a new block and returns its blockID.
+func (a *allocatorBuilder) alloc(data []byte) uint32 {
+	offset := align32(a.cursor)
+	order := blockOrder(len(data))
+	a.blocks = append(a.blocks, allocatedBlock{offset: offset, order: order, data: data})
+	a.cursor = offset + int64(uint32(1)<<order)
+	return uint32(len(a.blocks) - 1)
+}
+
+// Write serializes every staged record into b.content as a valid .DS_Store
+// image: B-tree leaf pages (split once a page would exceed dsBTreePageSize),
+// an internal root page when more than one leaf is needed, the DSDB master
+// block, and the buddy allocator bookkeeping area (TOC + empty free lists)
+// that ties it all together.
+func (b *DSStoreBuilder) Write(w io.Writer) error {
+	sorted := append([]builderRecord(nil), b.records...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].filename != sorted[j].filename {
+			return sorted[i].filename < sorted[j].filename
+		}
+		return sorted[i].structID < sorted[j].structID
+	})
+
+	encoded := make([][]byte, len(sorted))
+	for i, r := range sorted {
+		buf, err := encodeRecord(r)
+		if err != nil {
+			return err
+		}
+		encoded[i] = buf
+	}
+
+	var leaves [][]builderRecord
+	var leafRecBytes [][][]byte
+	var cur []builderRecord
+	var curRecBytes [][]byte
+	curLen := 0
+	const leafHeaderSize = 8 // rightmostChildID + numRecords
+	flush := func() {
+		if len(cur) == 0 {
+			return
+		}
+		leaves = append(leaves, cur)
+		leafRecBytes = append(leafRecBytes, curRecBytes)
+		cur = nil
+		curRecBytes = nil
+		curLen = 0
+	}
+	for i, rec := range encoded {
+		if curLen+len(rec)+leafHeaderSize > int(dsBTreePageSize) && len(cur) > 0 {
+			flush()
+		}
+		cur = append(cur, sorted[i])
+		curRecBytes = append(curRecBytes, rec)
+		curLen += len(rec)
+	}
+	flush()
+	if len(leaves) == 0 {
+		// An empty store is still a valid, if useless, .DS_Store: a root
+		// leaf with zero records.
+		leaves = [][]builderRecord{{}}
+		leafRecBytes = [][][]byte{{}}
+	}
+
+	// A B-tree record lives in exactly one node: either a leaf, or an
+	// internal node acting as the separator between two children. Promote
+	// each non-first leaf's leading record into the separator role instead
+	// of also leaving it behind in that leaf, so the total record count
+	// across the tree matches len(sorted) instead of double-counting.
+	var separators [][]byte
+	if len(leaves) > 1 {
+		separators = make([][]byte, len(leaves)-1)
+		for i := 1; i < len(leaves); i++ {
+			separators[i-1] = leafRecBytes[i][0]
+			leaves[i] = leaves[i][1:]
+			leafRecBytes[i] = leafRecBytes[i][1:]
+		}
+	}
+
+	allocatorOffset := int64(dsHeaderSize)
+	// Blocks must start after the allocator header, offset table, TOC, and
+	// free lists Write writes out below it — all of which are fixed-size
+	// regardless of how many records are staged.
+	firstBlockOffset := allocatorOffset + 4 + 8 + dsOffsetTableSize + dsTOCSize + dsNumFreeLists*4
+	alloc := newAllocatorBuilder(firstBlockOffset)
+
+	leafBlockIDs := make([]uint32, len(leaves))
+	for i, recBytes := range leafRecBytes {
+		var body bytes.Buffer
+		for _, rb := range recBytes {
+			body.Write(rb)
+		}
+		leaf := encodeLeafNode(0, uint32(len(leaves[i])), body.Bytes())
+		leafBlockIDs[i] = alloc.alloc(leaf)
+	}
+
+	var rootBlockID uint32
+	numInternalNodes := uint32(0)
+	if len(leaves) == 1 {
+		rootBlockID = leafBlockIDs[0]
+	} else {
+		numInternalNodes = 1
+		var root bytes.Buffer
+		for i := 0; i < len(leaves)-1; i++ {
+			binary.Write(&root, binary.BigEndian, leafBlockIDs[i])
+			root.Write(separators[i])
+		}
+		rootNode := encodeLeafNode(leafBlockIDs[len(leaves)-1], uint32(len(leaves)-1), root.Bytes())
+		rootBlockID = alloc.alloc(rootNode)
+	}
+
+	if len(alloc.blocks) > dsOffsetTableEntries {
+		return fmt.Errorf("too many blocks (%d) for this writer's %d-entry offset table; stage fewer records", len(alloc.blocks), dsOffsetTableEntries)
+	}
+
+	master := make([]byte, dsMasterBlockSize)
+	binary.BigEndian.PutUint32(master[0:4], rootBlockID)
+	binary.BigEndian.PutUint32(master[4:8], numInternalNodes)
+	binary.BigEndian.PutUint32(master[8:12], uint32(len(sorted)))
+	binary.BigEndian.PutUint32(master[12:16], uint32(len(leaves))+numInternalNodes)
+	binary.BigEndian.PutUint32(master[16:20], dsBTreePageSize)
+	masterBlockID := alloc.alloc(master)
+
+	var out bytes.Buffer
+
+	header := make([]byte, dsHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], 1)
+	copy(header[4:8], "Bud1")
+	binary.BigEndian.PutUint32(header[8:12], uint32(allocatorOffset))
+	bookkeepingSize := uint32(alloc.cursor - allocatorOffset)
+	binary.BigEndian.PutUint32(header[12:16], bookkeepingSize)
+	binary.BigEndian.PutUint32(header[16:20], uint32(allocatorOffset))
+	out.Write(header)
+
+	binary.Write(&out, binary.BigEndian, uint32(len(alloc.blocks)))
+	out.Write(make([]byte, 8)) // reserved
+
+	offsetTable := make([]byte, dsOffsetTableSize)
+	for id, blk := range alloc.blocks {
+		offsetAndSize := (uint32(blk.offset-4) &^ 0x1f) | blk.order
+		binary.BigEndian.PutUint32(offsetTable[id*4:id*4+4], offsetAndSize)
+	}
+	out.Write(offsetTable)
+
+	binary.Write(&out, binary.BigEndian, uint32(1)) // one TOC entry: "DSDB"
+	out.WriteByte(4)
+	out.WriteString("DSDB")
+	binary.Write(&out, binary.BigEndian, masterBlockID)
+
+	for i := 0; i < dsNumFreeLists; i++ {
+		binary.Write(&out, binary.BigEndian, uint32(0))
+	}
+
+	for _, blk := range alloc.blocks {
+		if int64(out.Len()) > blk.offset {
+			return fmt.Errorf("internal error: block at %d overlaps bookkeeping area (writer at %d)", blk.offset, out.Len())
+		}
+		out.Write(make([]byte, blk.offset-int64(out.Len())))
+		out.Write(blk.data)
+		padded := int64(uint32(1) << blk.order)
+		if pad := padded - int64(len(blk.data)); pad > 0 {
+			out.Write(make([]byte, pad))
+		}
+	}
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// encodeLeafNode wraps body (either a leaf's concatenated records or an
+// internal node's concatenated (childID, separator) pairs) with the
+// rightmostChildID/numRecords header parseNode expects.
+func encodeLeafNode(rightmostChildID, numRecords uint32, body []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, rightmostChildID)
+	binary.Write(&buf, binary.BigEndian, numRecords)
+	buf.Write(body)
+	return buf.Bytes()
+}
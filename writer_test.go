@@ -0,0 +1,116 @@
+// writer_test.go
+package dsstore
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestBuilderRoundTrip builds a small .DS_Store in memory, parses it back
+// with NewDSStore, and checks every staged record survives unchanged. There
+// is no Finder-produced sample in this repo to diff against byte-for-byte,
+// so this exercises the writer/parser pair against each other instead.
+func TestBuilderRoundTrip(t *testing.T) {
+	b := NewDSStoreBuilder()
+	if err := b.AddRecord("alpha.txt", "modD", "dutc", uint64(1234567890)); err != nil {
+		t.Fatalf("AddRecord modD: %v", err)
+	}
+	if err := b.AddRecord("alpha.txt", "vSrn", "long", uint32(1)); err != nil {
+		t.Fatalf("AddRecord vSrn: %v", err)
+	}
+	if err := b.AddRecord("beta.txt", "fwvh", "shor", uint32(400)); err != nil {
+		t.Fatalf("AddRecord fwvh: %v", err)
+	}
+	if err := b.SetIconLocation("beta.txt", 120, 240); err != nil {
+		t.Fatalf("SetIconLocation: %v", err)
+	}
+	if err := b.AddBplist("gamma.txt", "icvp", map[string]interface{}{"ShowStatusBar": true}); err != nil {
+		t.Fatalf("AddBplist: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	d, err := NewDSStore(buf.Bytes())
+	if err != nil {
+		t.Fatalf("NewDSStore on written image: %v", err)
+	}
+
+	got, ok := d.records["alpha.txt"]["modD"]
+	if !ok || got.(uint64) != 1234567890 {
+		t.Errorf("alpha.txt/modD = %v, want 1234567890", got)
+	}
+	if got, ok := d.records["beta.txt"]["fwvh"]; !ok || got.(uint32) != 400 {
+		t.Errorf("beta.txt/fwvh = %v, want 400", got)
+	}
+	iloc, ok := d.records["beta.txt"]["Iloc"].(IlocValue)
+	if !ok {
+		t.Fatalf("beta.txt/Iloc = %#v, want IlocValue", d.records["beta.txt"]["Iloc"])
+	}
+	if iloc.X != 120 || iloc.Y != 240 {
+		t.Errorf("beta.txt/Iloc = %+v, want {X:120 Y:240}", iloc)
+	}
+	icvp, ok := d.records["gamma.txt"]["icvp"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("gamma.txt/icvp = %#v, want a decoded plist map", d.records["gamma.txt"]["icvp"])
+	}
+	if show, ok := icvp["ShowStatusBar"].(bool); !ok || !show {
+		t.Errorf("gamma.txt/icvp[ShowStatusBar] = %v, want true", icvp["ShowStatusBar"])
+	}
+}
+
+// TestBuilderMultiLeafRecordCount forces Write to split records across more
+// than one B-tree leaf, then checks every staged record is seen exactly
+// once. A separator record promoted into the internal node must not also
+// be left behind in the leaf it was promoted from.
+func TestBuilderMultiLeafRecordCount(t *testing.T) {
+	const n = 500
+	b := NewDSStoreBuilder()
+	for i := 0; i < n; i++ {
+		if err := b.AddRecord(fmt.Sprintf("file-%04d.txt", i), "vSrn", "long", uint32(i)); err != nil {
+			t.Fatalf("AddRecord %d: %v", i, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	seen := make(map[string]int)
+	err := WalkDSStore(buf.Bytes(), func(filename, structID, dataType string, value interface{}) error {
+		seen[filename]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDSStore: %v", err)
+	}
+	if len(seen) != n {
+		t.Fatalf("got %d distinct filenames, want %d", len(seen), n)
+	}
+	for filename, count := range seen {
+		if count != 1 {
+			t.Errorf("%s seen %d times, want exactly once", filename, count)
+		}
+	}
+}
+
+// TestBuilderWriteRejectsTooManyBlocks checks that staging more records than
+// fit in the writer's fixed 256-entry offset table fails Write with an
+// error instead of panicking on an out-of-bounds slice write.
+func TestBuilderWriteRejectsTooManyBlocks(t *testing.T) {
+	b := NewDSStoreBuilder()
+	for i := 0; i < 50000; i++ {
+		if err := b.AddRecord(fmt.Sprintf("f%d", i), "vSrn", "long", uint32(i)); err != nil {
+			t.Fatalf("AddRecord %d: %v", i, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err == nil {
+		t.Fatal("Write with >256 blocks should error, not succeed")
+	}
+}